@@ -0,0 +1,200 @@
+package vsphere_vms
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// CloudInitUser defines a user account to be created by cloud-init on first boot.
+type CloudInitUser struct {
+	Name              string   `yaml:"name"`              // Login name of the user.
+	Sudo              string   `yaml:"sudo"`              // Sudoers entry, e.g. "ALL=(ALL) NOPASSWD:ALL".
+	Shell             string   `yaml:"shell"`             // Login shell, e.g. "/bin/bash".
+	SshAuthorizedKeys []string `yaml:"sshAuthorizedKeys"` // SSH public keys authorized for this user.
+}
+
+// CloudInitCfg defines module-wide cloud-init defaults that are applied to
+// every VM unless overridden by that VM's CloudInit field.
+type CloudInitCfg struct {
+	Users             []CloudInitUser `yaml:"users"`             // Default users created on every VM.
+	SshAuthorizedKeys []string        `yaml:"sshAuthorizedKeys"` // Default SSH keys appended to every VM.
+	AptSources        []string        `yaml:"aptSources"`        // Extra "apt: sources:" lines shared by every VM.
+}
+
+// CloudInit defines per-VM cloud-init provisioning data. At most one of
+// UserData, UserDataPath, or UserDataTemplate should be set; when none are
+// set, user-data is rendered entirely from CloudInitCfg defaults plus the
+// fields below.
+type CloudInit struct {
+	UserData          string            `yaml:"userData"`          // Inline cloud-config user-data.
+	UserDataPath      string            `yaml:"userDataPath"`      // Path to a file containing cloud-config user-data.
+	UserDataTemplate  string            `yaml:"userDataTemplate"`  // Path to a Go text/template rendered with TemplateValues.
+	TemplateValues    map[string]string `yaml:"templateValues"`    // Values made available to UserDataTemplate.
+	SshAuthorizedKeys []string          `yaml:"sshAuthorizedKeys"` // SSH keys appended to the CloudInitCfg defaults.
+	Packages          []string          `yaml:"packages"`          // Packages installed via the default-rendered user-data.
+	RunCmd            []string          `yaml:"runCmd"`            // Commands run on first boot via the default-rendered user-data.
+}
+
+// buildCloudInitExtraConfig renders the user-data, meta-data, and
+// network-config documents for vm, base64-encodes them, and returns the
+// guestinfo entries expected by the VMware cloud-init datasource.
+func buildCloudInitExtraConfig(vm VmData, networkCfg NetworkCfg, cloudInitCfg CloudInitCfg) (map[string]string, error) {
+	userData, err := renderUserData(vm, cloudInitCfg)
+	if err != nil {
+		return nil, fmt.Errorf("rendering user-data for vm %s: %w", vm.Name, err)
+	}
+	metaData := renderMetaData(vm)
+	networkConfig := renderNetworkConfigV2(vm, networkCfg)
+
+	return map[string]string{
+		"guestinfo.userdata":          base64.StdEncoding.EncodeToString([]byte(userData)),
+		"guestinfo.userdata.encoding": "base64",
+		"guestinfo.metadata":          base64.StdEncoding.EncodeToString([]byte(metaData + networkConfig)),
+		"guestinfo.metadata.encoding": "base64",
+	}, nil
+}
+
+// renderUserData produces the cloud-config user-data document for vm. It
+// honors CloudInit.UserData, UserDataPath, or UserDataTemplate in that
+// order of precedence; if none are set on vm, it renders a default
+// cloud-config from CloudInitCfg plus the per-VM fields.
+func renderUserData(vm VmData, cloudInitCfg CloudInitCfg) (string, error) {
+	if vm.CloudInit == nil {
+		return renderDefaultUserData(vm, cloudInitCfg, CloudInit{}), nil
+	}
+
+	ci := *vm.CloudInit
+	switch {
+	case ci.UserData != "":
+		return ci.UserData, nil
+	case ci.UserDataPath != "":
+		data, err := os.ReadFile(ci.UserDataPath)
+		if err != nil {
+			return "", fmt.Errorf("reading user-data path %s: %w", ci.UserDataPath, err)
+		}
+		return string(data), nil
+	case ci.UserDataTemplate != "":
+		tmplBytes, err := os.ReadFile(ci.UserDataTemplate)
+		if err != nil {
+			return "", fmt.Errorf("reading user-data template %s: %w", ci.UserDataTemplate, err)
+		}
+		tmpl, err := template.New(vm.Name + "-user-data").Parse(string(tmplBytes))
+		if err != nil {
+			return "", fmt.Errorf("parsing user-data template %s: %w", ci.UserDataTemplate, err)
+		}
+		var rendered bytes.Buffer
+		if err := tmpl.Execute(&rendered, ci.TemplateValues); err != nil {
+			return "", fmt.Errorf("executing user-data template %s: %w", ci.UserDataTemplate, err)
+		}
+		return rendered.String(), nil
+	default:
+		return renderDefaultUserData(vm, cloudInitCfg, ci), nil
+	}
+}
+
+// renderDefaultUserData builds a minimal cloud-config from the module-wide
+// defaults in cloudInitCfg, merged with the per-VM overrides in ci.
+func renderDefaultUserData(vm VmData, cloudInitCfg CloudInitCfg, ci CloudInit) string {
+	var b strings.Builder
+	b.WriteString("#cloud-config\n")
+	b.WriteString(fmt.Sprintf("hostname: %s\n", vm.HostName))
+	b.WriteString("ssh_pwauth: false\n")
+
+	if len(cloudInitCfg.Users) > 0 {
+		b.WriteString("users:\n")
+		for _, user := range cloudInitCfg.Users {
+			b.WriteString(fmt.Sprintf("  - name: %s\n", user.Name))
+			if user.Sudo != "" {
+				b.WriteString(fmt.Sprintf("    sudo: '%s'\n", user.Sudo))
+			}
+			if user.Shell != "" {
+				b.WriteString(fmt.Sprintf("    shell: %s\n", user.Shell))
+			}
+			keys := append(append([]string{}, user.SshAuthorizedKeys...), cloudInitCfg.SshAuthorizedKeys...)
+			writeSshAuthorizedKeys(&b, "    ", keys)
+		}
+	}
+
+	keys := append(append([]string{}, cloudInitCfg.SshAuthorizedKeys...), ci.SshAuthorizedKeys...)
+	writeSshAuthorizedKeys(&b, "", keys)
+
+	if len(cloudInitCfg.AptSources) > 0 {
+		b.WriteString("apt:\n  sources:\n")
+		for _, source := range cloudInitCfg.AptSources {
+			b.WriteString(fmt.Sprintf("    %s\n", source))
+		}
+	}
+
+	if len(ci.Packages) > 0 {
+		b.WriteString("packages:\n")
+		for _, pkg := range ci.Packages {
+			b.WriteString(fmt.Sprintf("  - %s\n", pkg))
+		}
+	}
+
+	if len(ci.RunCmd) > 0 {
+		b.WriteString("runcmd:\n")
+		for _, cmd := range ci.RunCmd {
+			b.WriteString(fmt.Sprintf("  - %s\n", cmd))
+		}
+	}
+
+	return b.String()
+}
+
+// writeSshAuthorizedKeys appends an indented "ssh_authorized_keys:" block to
+// b, if keys is non-empty.
+func writeSshAuthorizedKeys(b *strings.Builder, indent string, keys []string) {
+	if len(keys) == 0 {
+		return
+	}
+	b.WriteString(indent + "ssh_authorized_keys:\n")
+	for _, key := range keys {
+		b.WriteString(fmt.Sprintf("%s  - %s\n", indent, key))
+	}
+}
+
+// renderMetaData produces the cloud-init meta-data document for vm.
+func renderMetaData(vm VmData) string {
+	return fmt.Sprintf("instance-id: %s\nlocal-hostname: %s\n", vm.Name, vm.HostName)
+}
+
+// renderNetworkConfigV2 produces a cloud-init network-config version 2
+// document for vm, with one ethernet entry per configured network interface.
+func renderNetworkConfigV2(vm VmData, networkCfg NetworkCfg) string {
+	var b strings.Builder
+	b.WriteString("network:\n  version: 2\n  ethernets:\n")
+	for i, nic := range vm.NetworkInterfaces {
+		ifaceName := fmt.Sprintf("eth%d", i)
+		b.WriteString(fmt.Sprintf("    %s:\n", ifaceName))
+		if nic.Dhcp {
+			b.WriteString("      dhcp4: true\n")
+			continue
+		}
+		addresses := []string{fmt.Sprintf("%s/%d", nic.Ipv4Address, nic.Ipv4PrefixLen)}
+		if nic.Ipv6Address != "" {
+			addresses = append(addresses, fmt.Sprintf("%s/%d", nic.Ipv6Address, nic.Ipv6PrefixLen))
+		}
+		b.WriteString(fmt.Sprintf("      addresses: [%s]\n", strings.Join(addresses, ", ")))
+		if nic.Ipv4Gateway != "" {
+			b.WriteString(fmt.Sprintf("      gateway4: %s\n", nic.Ipv4Gateway))
+		}
+		if nic.Ipv6Gateway != "" {
+			b.WriteString(fmt.Sprintf("      gateway6: %s\n", nic.Ipv6Gateway))
+		}
+		if len(networkCfg.DnsServers) > 0 || len(networkCfg.DnsSuffixes) > 0 {
+			b.WriteString("      nameservers:\n")
+			if len(networkCfg.DnsServers) > 0 {
+				b.WriteString(fmt.Sprintf("        addresses: [%s]\n", strings.Join(networkCfg.DnsServers, ", ")))
+			}
+			if len(networkCfg.DnsSuffixes) > 0 {
+				b.WriteString(fmt.Sprintf("        search: [%s]\n", strings.Join(networkCfg.DnsSuffixes, ", ")))
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,165 @@
+package vsphere_vms
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-vsphere/sdk/v4/go/vsphere"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// SnapshotCfg defines an initial post-clone snapshot to take of a VM.
+type SnapshotCfg struct {
+	Name        string `yaml:"name"`        // Snapshot name, e.g. "baseline".
+	Description string `yaml:"description"` // Snapshot description.
+	MemoryDump  bool   `yaml:"memoryDump"`  // Whether to include the VM's memory state in the snapshot.
+	Quiesce     bool   `yaml:"quiesce"`     // Whether to quiesce the guest filesystem before snapshotting.
+}
+
+// mergeDefaultTags returns a copy of vms with defaultTags prepended to each VM's own Tags.
+// When defaultTags is empty, vms itself is returned unchanged.
+func mergeDefaultTags(vms []VmData, defaultTags []string) []VmData {
+	if len(defaultTags) == 0 {
+		return vms
+	}
+	merged := make([]VmData, len(vms))
+	for i, vm := range vms {
+		vm.Tags = append(append([]string{}, defaultTags...), vm.Tags...)
+		merged[i] = vm
+	}
+	return merged
+}
+
+// resolveTagIds resolves "Category:TagName" references to their vSphere tag IDs, keyed
+// by the original reference string. When manageTags is set, every referenced category and
+// tag is created as a Pulumi-managed resource; whether it already exists in vSphere is not
+// checked, so existing categories and tags are not reused. Otherwise they are looked up
+// and must already exist.
+func resolveTagIds(ctx *pulumi.Context, manageTags bool, tagRefs []string, opts ...pulumi.ResourceOption) (map[string]pulumi.StringInput, error) {
+	tagIds := map[string]pulumi.StringInput{}
+	categoryIds := map[string]pulumi.StringInput{}
+
+	for _, ref := range tagRefs {
+		if _, ok := tagIds[ref]; ok {
+			continue
+		}
+		category, tagName, err := splitTagRef(ref)
+		if err != nil {
+			return nil, err
+		}
+
+		categoryId, ok := categoryIds[category]
+		if !ok {
+			categoryId, err = resolveTagCategoryId(ctx, manageTags, category, opts...)
+			if err != nil {
+				return nil, err
+			}
+			categoryIds[category] = categoryId
+		}
+
+		tagId, err := resolveTagId(ctx, manageTags, category, tagName, categoryId, opts...)
+		if err != nil {
+			return nil, err
+		}
+		tagIds[ref] = tagId
+	}
+
+	return tagIds, nil
+}
+
+// resolveTagCategoryId creates or looks up the tag category named category, per manageTags.
+func resolveTagCategoryId(ctx *pulumi.Context, manageTags bool, category string, opts ...pulumi.ResourceOption) (pulumi.StringInput, error) {
+	if manageTags {
+		cat, err := vsphere.NewTagCategory(ctx, "tag-category-"+category, &vsphere.TagCategoryArgs{
+			Name:            pulumi.String(category),
+			Cardinality:     pulumi.String("MULTIPLE"),
+			AssociableTypes: pulumi.StringArray{pulumi.String("VirtualMachine")},
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return cat.ID(), nil
+	}
+
+	lookup, err := vsphere.LookupTagCategory(ctx, &vsphere.LookupTagCategoryArgs{Name: category})
+	if err != nil {
+		return nil, err
+	}
+	return pulumi.String(lookup.Id), nil
+}
+
+// resolveTagId creates or looks up the tag tagName under categoryId, per manageTags.
+func resolveTagId(ctx *pulumi.Context, manageTags bool, category string, tagName string, categoryId pulumi.StringInput, opts ...pulumi.ResourceOption) (pulumi.StringInput, error) {
+	if manageTags {
+		tag, err := vsphere.NewTag(ctx, "tag-"+category+"-"+tagName, &vsphere.TagArgs{
+			Name:       pulumi.String(tagName),
+			CategoryId: categoryId,
+		}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return tag.ID(), nil
+	}
+
+	categoryIdStr, ok := categoryId.(pulumi.String)
+	if !ok {
+		return nil, fmt.Errorf("tag %s:%s: category id is only known at apply time; set manageTags to create it instead", category, tagName)
+	}
+	lookup, err := vsphere.LookupTag(ctx, &vsphere.LookupTagArgs{
+		Name:       tagName,
+		CategoryId: string(categoryIdStr),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pulumi.String(lookup.Id), nil
+}
+
+// splitTagRef splits a "Category:TagName" reference into its category and tag name.
+func splitTagRef(ref string) (category string, tagName string, err error) {
+	parts := strings.SplitN(ref, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("tag reference %q must be in the form \"Category:TagName\"", ref)
+	}
+	return parts[0], parts[1], nil
+}
+
+// resolveCustomAttributeIds looks up the vSphere custom attribute definitions named in
+// names, which must already exist, and returns their IDs keyed by name.
+func resolveCustomAttributeIds(ctx *pulumi.Context, names []string) (map[string]string, error) {
+	ids := map[string]string{}
+	for _, name := range names {
+		if _, ok := ids[name]; ok {
+			continue
+		}
+		attribute, err := vsphere.LookupCustomAttribute(ctx, &vsphere.LookupCustomAttributeArgs{Name: name})
+		if err != nil {
+			return nil, err
+		}
+		ids[name] = attribute.Id
+	}
+	return ids, nil
+}
+
+// createSnapshots creates the initial post-clone snapshot configured on each VM via
+// VmData.Snapshot. vms and virtualMachines must be the same length and index-aligned,
+// as produced by createVms.
+func createSnapshots(ctx *pulumi.Context, vms []VmData, virtualMachines []*vsphere.VirtualMachine, opts ...pulumi.ResourceOption) error {
+	for i, vm := range vms {
+		if vm.Snapshot == nil || virtualMachines[i] == nil {
+			continue
+		}
+		if _, err := vsphere.NewVirtualMachineSnapshot(ctx, vm.Name+"-"+vm.Snapshot.Name, &vsphere.VirtualMachineSnapshotArgs{
+			VmUuid:         virtualMachines[i].Uuid,
+			SnapshotName:   pulumi.String(vm.Snapshot.Name),
+			Description:    pulumi.String(vm.Snapshot.Description),
+			Memory:         pulumi.Bool(vm.Snapshot.MemoryDump),
+			Quiesce:        pulumi.Bool(vm.Snapshot.Quiesce),
+			RemoveChildren: pulumi.Bool(false),
+			Consolidate:    pulumi.Bool(true),
+		}, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package vsphere_vms
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-vsphere/sdk/v4/go/vsphere"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Placement controls where VMs created by VsphereVms are scheduled: which resource
+// pool and (optionally) ESXi host they land on, and what DRS affinity/anti-affinity
+// rules are created over them.
+type Placement struct {
+	ResourcePool       string     `yaml:"resourcePool"`       // Resource pool under the cluster to place VMs into. Defaults to the cluster's root resource pool.
+	HostSystem         string     `yaml:"hostSystem"`         // Optional ESXi host to pin every VM to.
+	AntiAffinityGroups [][]string `yaml:"antiAffinityGroups"` // Groups of VM names that must run on different hosts.
+	AffinityGroups     [][]string `yaml:"affinityGroups"`     // Groups of VM names that must run on the same host.
+}
+
+// resolvePlacement resolves placement.ResourcePool and placement.HostSystem into their
+// vSphere managed object IDs, defaulting to the cluster's root resource pool when no
+// resource pool is configured.
+func resolvePlacement(ctx *pulumi.Context, datacenter *vsphere.LookupDatacenterResult, cluster *vsphere.LookupComputeClusterResult, vsphereCfg VsphereCfg, placement Placement) (resourcePoolId string, hostSystemId *string, err error) {
+	resourcePoolId = cluster.ResourcePoolId
+	if placement.ResourcePool != "" {
+		pool, err := vsphere.LookupResourcePool(ctx, &vsphere.LookupResourcePoolArgs{
+			Name:         fmt.Sprintf("%s/Resources/%s", vsphereCfg.Cluster, placement.ResourcePool),
+			DatacenterId: pulumi.StringRef(datacenter.Id),
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		resourcePoolId = pool.Id
+	}
+
+	if placement.HostSystem != "" {
+		host, err := vsphere.LookupHost(ctx, &vsphere.LookupHostArgs{
+			Name:         pulumi.StringRef(placement.HostSystem),
+			DatacenterId: pulumi.StringRef(datacenter.Id),
+		})
+		if err != nil {
+			return "", nil, err
+		}
+		hostSystemId = &host.Id
+	}
+
+	return resourcePoolId, hostSystemId, nil
+}
+
+// registerAffinityRules creates DRS anti-affinity and affinity rules over the virtual
+// machines created by NewVsphereVms, per placement.AntiAffinityGroups and
+// placement.AffinityGroups. vms and virtualMachines must be the same length and
+// index-aligned, as produced by createVms.
+func registerAffinityRules(ctx *pulumi.Context, lookupData *LookupData, placement Placement, vms []VmData, virtualMachines []*vsphere.VirtualMachine, opts ...pulumi.ResourceOption) error {
+	vmIdByName := map[string]pulumi.IDOutput{}
+	for i, vm := range vms {
+		if virtualMachines[i] != nil {
+			vmIdByName[vm.Name] = virtualMachines[i].ID()
+		}
+	}
+
+	for i, group := range placement.AntiAffinityGroups {
+		if len(group) < 2 {
+			return fmt.Errorf("placement.antiAffinityGroups[%d]: a group needs at least 2 vm names, got %d", i, len(group))
+		}
+		ids, err := vmIdsForGroup(vmIdByName, group)
+		if err != nil {
+			return err
+		}
+		if _, err := vsphere.NewComputeClusterVmAntiAffinityRule(ctx, fmt.Sprintf("anti-affinity-%d", i), &vsphere.ComputeClusterVmAntiAffinityRuleArgs{
+			ComputeClusterId:  pulumi.String(lookupData.Cluster.Id),
+			VirtualMachineIds: ids,
+		}, opts...); err != nil {
+			return err
+		}
+	}
+
+	for i, group := range placement.AffinityGroups {
+		if len(group) < 2 {
+			return fmt.Errorf("placement.affinityGroups[%d]: a group needs at least 2 vm names, got %d", i, len(group))
+		}
+		ids, err := vmIdsForGroup(vmIdByName, group)
+		if err != nil {
+			return err
+		}
+		if _, err := vsphere.NewComputeClusterVmAffinityRule(ctx, fmt.Sprintf("affinity-%d", i), &vsphere.ComputeClusterVmAffinityRuleArgs{
+			ComputeClusterId:  pulumi.String(lookupData.Cluster.Id),
+			VirtualMachineIds: ids,
+		}, opts...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// vmIdsForGroup resolves a placement group's VM names to the IDs of the VMs created by
+// NewVsphereVms.
+func vmIdsForGroup(vmIdByName map[string]pulumi.IDOutput, group []string) (pulumi.StringArray, error) {
+	ids := pulumi.StringArray{}
+	for _, name := range group {
+		id, ok := vmIdByName[name]
+		if !ok {
+			return nil, fmt.Errorf("placement group references unknown vm %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,119 @@
+package vsphere_vms
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// RetryPolicy controls how transient per-VM provisioning errors are retried.
+type RetryPolicy struct {
+	MaxAttempts     int           `yaml:"maxAttempts"`     // Maximum attempts per VM, including the first. Defaults to 1 (no retry) when unset.
+	InitialBackoff  time.Duration `yaml:"initialBackoff"`  // Backoff before the first retry.
+	MaxBackoff      time.Duration `yaml:"maxBackoff"`      // Upper bound on backoff between retries.
+	Jitter          time.Duration `yaml:"jitter"`          // Maximum random jitter added to each backoff.
+	RetryableErrors []string      `yaml:"retryableErrors"` // Substrings that mark an error as transient and worth retrying, e.g. "customization", "timeout waiting for IP", "resource in use".
+}
+
+// maxAttempts returns policy.MaxAttempts, defaulting to 1 (no retry) when unset.
+func (policy RetryPolicy) maxAttempts() int {
+	if policy.MaxAttempts <= 0 {
+		return 1
+	}
+	return policy.MaxAttempts
+}
+
+// isRetryable reports whether err matches one of policy's RetryableErrors substrings.
+func (policy RetryPolicy) isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, substr := range policy.RetryableErrors {
+		if strings.Contains(msg, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+// withRetry calls fn once per attempt (1-indexed), retrying on errors that
+// policy classifies as transient with exponential backoff and jitter, and
+// returns the last error if every attempt fails. fn receives the attempt
+// number so callers that register a Pulumi resource can give each attempt
+// its own resource name — RegisterResource rejects a second call for a URN
+// it has already seen in the same program run, so simply calling fn again
+// with the same identity would fail immediately on retry rather than
+// reattempting the underlying operation.
+func withRetry(policy RetryPolicy, fn func(attempt int) error) error {
+	maxAttempts := policy.maxAttempts()
+
+	backoff := policy.InitialBackoff
+	if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+		backoff = policy.MaxBackoff
+	}
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		lastErr = fn(attempt)
+		if lastErr == nil {
+			return nil
+		}
+		if attempt == maxAttempts || !policy.isRetryable(lastErr) {
+			return lastErr
+		}
+
+		sleep := backoff
+		if policy.Jitter > 0 {
+			sleep += time.Duration(rand.Int63n(int64(policy.Jitter)))
+		}
+		time.Sleep(sleep)
+
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+	return lastErr
+}
+
+// retryAttemptName returns the Pulumi resource name used to register vmName's
+// clone on the given attempt: vmName itself on the first attempt, and a
+// "<vmName>-retryN" name on subsequent attempts (see withRetry).
+func retryAttemptName(vmName string, attempt int) string {
+	if attempt <= 1 {
+		return vmName
+	}
+	return fmt.Sprintf("%s-retry%d", vmName, attempt)
+}
+
+// retryResourceNameAndAliases returns the resource name vmName's clone should
+// be registered under for the given attempt, plus aliases covering every
+// other name the clone could have been registered under on any attempt up to
+// retryPolicy's MaxAttempts. A retryable failure forces each attempt within a
+// single run to register under its own resource name, since RegisterResource
+// rejects a second call for a URN it already saw in that run even if the
+// first attempt's Create failed. Listing every other attempt's name as an
+// alias on every call, on every run — not just the run where a retry actually
+// happened — ensures whichever name ended up checkpointed in state from a
+// prior run is recognized again instead of being replaced once some later
+// run's first attempt succeeds under a different name.
+func retryResourceNameAndAliases(vmName string, retryPolicy RetryPolicy, attempt int) (string, []pulumi.Alias) {
+	maxAttempts := retryPolicy.maxAttempts()
+	if maxAttempts <= 1 {
+		return vmName, nil
+	}
+
+	name := retryAttemptName(vmName, attempt)
+	var aliases []pulumi.Alias
+	for n := 1; n <= maxAttempts; n++ {
+		other := retryAttemptName(vmName, n)
+		if other == name {
+			continue
+		}
+		aliases = append(aliases, pulumi.Alias{Name: pulumi.String(other)})
+	}
+	return name, aliases
+}
@@ -1,56 +1,117 @@
 package vsphere_vms
 
 import (
+	"errors"
+	"fmt"
+	"sync"
+
 	"github.com/pulumi/pulumi-vsphere/sdk/v4/go/vsphere"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
 )
 
 // VmData defines the structure for a virtual machine's data.
-// It includes specifications for the VM's name, network configuration, and hardware resources.
+// It includes specifications for the VM's name, disks, network interfaces, and hardware resources.
 // Each field is tagged with `yaml` annotations for easy deserialization from configuration files.
 type VmData struct {
-	Name        string `yaml:"name"`        // Name of the virtual machine.
-	HostName    string `yaml:"hostName"`    // Hostname of the virtual machine.
-	Ipv4Address string `yaml:"ipv4address"` // IPv4 address of the virtual machine.
-	NumCpus     int    `yaml:"numCpus"`     // Number of CPUs for the virtual machine.
-	Memory      int    `yaml:"memory"`      // Memory size in MB for the virtual machine.
+	Name              string                `yaml:"name"`              // Name of the virtual machine.
+	HostName          string                `yaml:"hostName"`          // Hostname of the virtual machine.
+	NumCpus           int                   `yaml:"numCpus"`           // Number of CPUs for the virtual machine.
+	Memory            int                   `yaml:"memory"`            // Memory size in MB for the virtual machine.
+	Disks             []DiskCfg             `yaml:"disks"`             // Disks to attach to the virtual machine. Defaults to a single disk cloned from the template if empty; required with a Content Library template source.
+	NetworkInterfaces []NetworkInterfaceCfg `yaml:"networkInterfaces"` // Network interfaces to attach to the virtual machine.
+	CloudInit         *CloudInit            `yaml:"cloudInit"`         // Optional per-VM cloud-init settings. When nil, user-data is rendered from CloudInitCfg defaults alone.
+	Snapshot          *SnapshotCfg          `yaml:"snapshot"`          // Optional initial snapshot to take after cloning.
+	Tags              []string              `yaml:"tags"`              // Tags to attach, as "Category:TagName" strings.
+	CustomAttributes  map[string]string     `yaml:"customAttributes"`  // vSphere custom attributes to set on the VM, keyed by attribute name.
+}
+
+// DiskCfg defines a single disk to attach to a virtual machine.
+type DiskCfg struct {
+	Label           string `yaml:"label"`           // Disk label, e.g. "disk0".
+	SizeGb          int    `yaml:"sizeGb"`          // Disk size in GB.
+	ThinProvisioned bool   `yaml:"thinProvisioned"` // Whether the disk is thin-provisioned.
+	EagerlyScrub    bool   `yaml:"eagerlyScrub"`    // Whether the disk is eagerly scrubbed.
+	ControllerType  string `yaml:"controllerType"`  // Disk controller type, e.g. "scsi-paravirtual", "scsi-lsi-sas", "ide".
+	Datastore       string `yaml:"datastore"`       // Optional datastore override; defaults to VsphereCfg.Datastore.
+}
+
+// NetworkInterfaceCfg defines a single network interface to attach to a virtual machine.
+// Set Dhcp to rely on DHCP instead of the static Ipv4/Ipv6 fields below.
+type NetworkInterfaceCfg struct {
+	NetworkName   string `yaml:"networkName"`   // Name of the vSphere network to attach to.
+	AdapterType   string `yaml:"adapterType"`   // Network adapter type, e.g. "vmxnet3", "e1000".
+	Dhcp          bool   `yaml:"dhcp"`          // Use DHCP instead of the static addressing fields below.
+	Ipv4Address   string `yaml:"ipv4Address"`   // Static IPv4 address.
+	Ipv4PrefixLen int    `yaml:"ipv4PrefixLen"` // Static IPv4 network prefix length.
+	Ipv4Gateway   string `yaml:"ipv4Gateway"`   // IPv4 gateway. Only one interface per VM should set this.
+	Ipv6Address   string `yaml:"ipv6Address"`   // Optional static IPv6 address.
+	Ipv6PrefixLen int    `yaml:"ipv6PrefixLen"` // Optional static IPv6 network prefix length.
+	Ipv6Gateway   string `yaml:"ipv6Gateway"`   // Optional IPv6 gateway. Only one interface per VM should set this.
+	MacAddress    string `yaml:"macAddress"`    // Optional static MAC address.
 }
 
 // VsphereCfg defines the vSphere-specific configuration required for creating virtual machines.
 // This includes details about the vSphere environment, such as datacenter, datastore, and cluster information.
 // It also specifies the template to be used for cloning new VMs.
 type VsphereCfg struct {
-	Datacenter     string `yaml:"datacenter"`     // Name of the vSphere datacenter.
-	Datastore      string `yaml:"datastore"`      // Name of the vSphere datastore.
-	Cluster        string `yaml:"cluster"`        // Name of the vSphere cluster.
-	NetworkName    string `yaml:"networkName"`    // Name of the vSphere network.
-	TemplateName   string `yaml:"templateName"`   // Name of the VM template to clone from.
-	TemplateFolder string `yaml:"templateFolder"` // Folder containing the VM template.
-	VmsFolder      string `yaml:"vmsFolder"`      // Folder to place the new virtual machines in.
-	EnableLogging  bool   `yaml:"enableLogging"`  // Enable logging for the virtual machine.
-	EnableDiskUuid bool   `yaml:"enableDiskUuid"` // Enable disk UUID for the virtual machine.
-}
-
-// NetworkCfg defines the network configuration for the virtual machines.
-// This includes gateway, DNS servers, DNS suffixes, domain, and subnet mask.
+	Datacenter     string         `yaml:"datacenter"`     // Name of the vSphere datacenter.
+	Datastore      string         `yaml:"datastore"`      // Name of the vSphere datastore.
+	Cluster        string         `yaml:"cluster"`        // Name of the vSphere cluster.
+	Template       TemplateSource `yaml:"template"`       // Where to clone/deploy the VM template from.
+	VmsFolder      string         `yaml:"vmsFolder"`      // Folder to place the new virtual machines in.
+	EnableLogging  bool           `yaml:"enableLogging"`  // Enable logging for the virtual machine.
+	EnableDiskUuid bool           `yaml:"enableDiskUuid"` // Enable disk UUID for the virtual machine.
+}
+
+// TemplateSource selects where a VM template is cloned or deployed from.
+// Exactly one of Inventory or ContentLibrary should be set.
+type TemplateSource struct {
+	Inventory      *InventoryTemplateSource      `yaml:"inventory"`      // Clone from an existing inventory VM template.
+	ContentLibrary *ContentLibraryTemplateSource `yaml:"contentLibrary"` // Clone/deploy from a Content Library item.
+}
+
+// InventoryTemplateSource clones from an existing VM template already present in vSphere inventory.
+type InventoryTemplateSource struct {
+	Name   string `yaml:"name"`   // Name of the VM template to clone from.
+	Folder string `yaml:"folder"` // Folder containing the VM template.
+}
+
+// ContentLibraryTemplateSource clones from a Content Library item. Both "vmtx"
+// and "ovf" (OVF/OVA) items are cloned the same way, via Clone.TemplateUuid set
+// to the resolved item's ID; the vsphere provider has no field that accepts an
+// OVF deploy from a content-library item reference, so there is no separate
+// OVF-deploy path here.
+type ContentLibraryTemplateSource struct {
+	Library string `yaml:"library"` // Name of the Content Library.
+	Item    string `yaml:"item"`    // Name of the Content Library item.
+	Type    string `yaml:"type"`    // "vmtx" or "ovf". Used only to disambiguate the item lookup.
+}
+
+// NetworkCfg defines network defaults shared by every virtual machine's network interfaces.
+// NetworkInterfaceCfg has no DNS or domain fields, so these values apply to every interface.
 type NetworkCfg struct {
-	Gateway     string   `yaml:"gateway"`     // Network gateway IP address.
 	DnsServers  []string `yaml:"dnsServers"`  // List of DNS server IP addresses.
 	DnsSuffixes []string `yaml:"dnsSuffixes"` // List of DNS suffixes.
 	Domain      string   `yaml:"domain"`      // Network domain name.
-	Mask        int      `yaml:"mask"`        // Subnet mask.
 }
 
 // LookupData holds the results of vSphere lookups.
-// It stores references to the datacenter, cluster, datastore, template VM, and network.
-// This data is used to provision new virtual machines.
+// It stores references to the datacenter, cluster, datastore, the resolved template
+// source, and every network referenced by the VMs being created, keyed by network name.
+// Exactly one of TemplateVm or ContentLibraryItem is set, matching VsphereCfg.Template.
 type LookupData struct {
-	Datacenter *vsphere.LookupDatacenterResult
-	Cluster    *vsphere.LookupComputeClusterResult
-	Datastore  *vsphere.GetDatastoreResult
-	TemplateVm *vsphere.LookupVirtualMachineResult
-	Network    *vsphere.GetNetworkResult
+	Datacenter         *vsphere.LookupDatacenterResult
+	Cluster            *vsphere.LookupComputeClusterResult
+	Datastore          *vsphere.GetDatastoreResult
+	Datastores         map[string]*vsphere.GetDatastoreResult // Per-disk datastore overrides, keyed by DiskCfg.Datastore.
+	TemplateVm         *vsphere.LookupVirtualMachineResult
+	ContentLibraryItem *vsphere.GetContentLibraryItemResult
+	Network            map[string]*vsphere.GetNetworkResult
+	ResourcePoolId     string                        // Resolved from Placement.ResourcePool, or the cluster's root resource pool.
+	HostSystemId       *string                       // Resolved from Placement.HostSystem, if set.
+	TagIds             map[string]pulumi.StringInput // Resolved tag IDs, keyed by "Category:TagName" reference.
+	CustomAttributeIds map[string]string             // Resolved custom attribute definition IDs, keyed by attribute name.
 }
 
 // VsphereVms is a Pulumi component resource for managing a group of vSphere virtual machines.
@@ -63,9 +124,15 @@ type VsphereVms struct {
 // VsphereVmsArgs defines the arguments for creating a VsphereVms component.
 // It includes a list of VM data, vSphere configuration, and network configuration.
 type VsphereVmsArgs struct {
-	Vms        []VmData
-	VsphereCfg VsphereCfg
-	NetworkCfg NetworkCfg
+	Vms          []VmData
+	VsphereCfg   VsphereCfg
+	NetworkCfg   NetworkCfg
+	CloudInitCfg CloudInitCfg // Module-wide cloud-init defaults shared by every VM.
+	Concurrency  int          // Maximum number of VMs to provision at once. Defaults to 1 (sequential) when unset.
+	RetryPolicy  RetryPolicy  // Retry behavior for transient per-VM provisioning errors.
+	Placement    Placement    // Resource pool/host placement and DRS affinity rules for the created VMs.
+	DefaultTags  []string     // Tags, as "Category:TagName" strings, applied to every VM in addition to its own Tags.
+	ManageTags   bool         // Whether to create every referenced tag category/tag as a managed resource instead of requiring they already exist. Existing ones are not reused.
 }
 
 // NewVsphereVmsFromConfig creates a new VsphereVms component by reading configuration from Pulumi config.
@@ -76,6 +143,16 @@ func NewVsphereVmsFromConfig(ctx *pulumi.Context, name string, opts ...pulumi.Re
 	cfg.RequireObject("vms", &vsphereVmsArgs.Vms)
 	cfg.RequireObject("vsphereCfg", &vsphereVmsArgs.VsphereCfg)
 	cfg.RequireObject("networkCfg", &vsphereVmsArgs.NetworkCfg)
+	cfg.TryObject("cloudInitCfg", &vsphereVmsArgs.CloudInitCfg)
+	if concurrency, err := cfg.TryInt("concurrency"); err == nil {
+		vsphereVmsArgs.Concurrency = concurrency
+	}
+	cfg.TryObject("retryPolicy", &vsphereVmsArgs.RetryPolicy)
+	cfg.TryObject("placement", &vsphereVmsArgs.Placement)
+	cfg.TryObject("defaultTags", &vsphereVmsArgs.DefaultTags)
+	if manageTags, err := cfg.TryBool("manageTags"); err == nil {
+		vsphereVmsArgs.ManageTags = manageTags
+	}
 
 	return NewVsphereVms(ctx, name, &vsphereVmsArgs, opts...)
 }
@@ -89,18 +166,24 @@ func NewVsphereVms(ctx *pulumi.Context, name string, args *VsphereVmsArgs, opts
 		return nil, err
 	}
 
-	lookupData, err := lookupData(ctx, args.VsphereCfg, args.NetworkCfg)
+	vms := mergeDefaultTags(args.Vms, args.DefaultTags)
+
+	lookupData, err := lookupData(ctx, args, vms, pulumi.Parent(vsphereVms))
+	if err != nil {
+		return nil, err
+	}
+
+	virtualMachines, err := createVms(ctx, lookupData, args, vms, pulumi.Parent(vsphereVms))
 	if err != nil {
 		return nil, err
 	}
 
-	var virtualMachines []*vsphere.VirtualMachine
-	for _, vm := range args.Vms {
-		newVm, err := createVm(ctx, lookupData, args.NetworkCfg, args.VsphereCfg, vm, pulumi.Parent(vsphereVms))
-		if err != nil {
-			return nil, err
-		}
-		virtualMachines = append(virtualMachines, newVm)
+	if err := registerAffinityRules(ctx, lookupData, args.Placement, vms, virtualMachines, pulumi.Parent(vsphereVms)); err != nil {
+		return nil, err
+	}
+
+	if err := createSnapshots(ctx, vms, virtualMachines, pulumi.Parent(vsphereVms)); err != nil {
+		return nil, err
 	}
 
 	vsphereVms.VirtualMachines = virtualMachines
@@ -112,79 +195,276 @@ func NewVsphereVms(ctx *pulumi.Context, name string, args *VsphereVmsArgs, opts
 	return vsphereVms, nil
 }
 
-// createVm creates a single virtual machine in vSphere.
+// createVms provisions every VM in vms, fanning out up to args.Concurrency
+// worker goroutines and retrying transient per-VM failures per args.RetryPolicy.
+// Errors from every VM are aggregated with errors.Join. The returned slice
+// preserves the order of vms regardless of completion order, so Pulumi
+// state stays stable across runs.
+func createVms(ctx *pulumi.Context, lookupData *LookupData, args *VsphereVmsArgs, vms []VmData, opts ...pulumi.ResourceOption) ([]*vsphere.VirtualMachine, error) {
+	concurrency := args.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]*vsphere.VirtualMachine, len(vms))
+	errs := make([]error, len(vms))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, vm := range vms {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, vm VmData) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			errs[i] = withRetry(args.RetryPolicy, func(attempt int) error {
+				newVm, err := createVm(ctx, lookupData, args.NetworkCfg, args.VsphereCfg, args.CloudInitCfg, vm, args.RetryPolicy, attempt, opts...)
+				if err != nil {
+					return err
+				}
+				results[i] = newVm
+				return nil
+			})
+		}(i, vm)
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// createVm creates a single virtual machine in vSphere. attempt is the
+// 1-indexed retry attempt (see withRetry). RegisterResource rejects a second
+// registration for a URN it already saw in this program run, even if the
+// first attempt's Create failed, so each attempt up to retryPolicy's
+// MaxAttempts registers under its own resource name (see
+// retryResourceNameAndAliases). Every possible attempt name is aliased to
+// every other one on every run, regardless of which attempt this particular
+// run actually needed, so that whichever name ends up checkpointed in state
+// is recognized again on the next run instead of being replaced.
 // It uses the provided lookup data and configuration to clone a new VM from a template.
-func createVm(ctx *pulumi.Context, lookupData *LookupData, networkCfg NetworkCfg, vsphereCfg VsphereCfg, vm VmData, opts ...pulumi.ResourceOption) (*vsphere.VirtualMachine, error) {
+func createVm(ctx *pulumi.Context, lookupData *LookupData, networkCfg NetworkCfg, vsphereCfg VsphereCfg, cloudInitCfg CloudInitCfg, vm VmData, retryPolicy RetryPolicy, attempt int, opts ...pulumi.ResourceOption) (*vsphere.VirtualMachine, error) {
 	templateVm := lookupData.TemplateVm
 	const net_timeout = 300
-	newVm, err := vsphere.NewVirtualMachine(ctx, vm.Name, &vsphere.VirtualMachineArgs{
+
+	disks, err := getVmCloneDiskArray(lookupData, templateVm, vm)
+	if err != nil {
+		return nil, err
+	}
+
+	networkInterfaces, err := getVmCloneNetworkInterfaceArray(lookupData, vm)
+	if err != nil {
+		return nil, err
+	}
+
+	extraConfig, err := buildCloudInitExtraConfig(vm, networkCfg, cloudInitCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	tagIds := pulumi.StringArray{}
+	for _, ref := range vm.Tags {
+		id, ok := lookupData.TagIds[ref]
+		if !ok {
+			return nil, fmt.Errorf("vm %s: tag %q was not resolved during lookup", vm.Name, ref)
+		}
+		tagIds = append(tagIds, id)
+	}
+
+	customAttributes := pulumi.StringMap{}
+	for name, value := range vm.CustomAttributes {
+		id, ok := lookupData.CustomAttributeIds[name]
+		if !ok {
+			return nil, fmt.Errorf("vm %s: custom attribute %q was not resolved during lookup", vm.Name, name)
+		}
+		customAttributes[id] = pulumi.String(value)
+	}
+
+	vmArgs := &vsphere.VirtualMachineArgs{
 		Name:                   pulumi.String(vm.Name),
-		ResourcePoolId:         pulumi.String(lookupData.Cluster.ResourcePoolId),
+		ResourcePoolId:         pulumi.String(lookupData.ResourcePoolId),
+		HostSystemId:           pulumi.StringPtrFromPtr(lookupData.HostSystemId),
 		DatastoreId:            pulumi.String(lookupData.Datastore.Id),
 		NumCpus:                pulumi.Int(vm.NumCpus),
 		Memory:                 pulumi.Int(vm.Memory),
-		Clone:                  getVMCloneArgs(lookupData, networkCfg, vm),
-		Disks:                  getVmCloneDiskArray(templateVm),
-		NetworkInterfaces:      getVmCloneNetworkInterfaceArray(templateVm, lookupData.Network),
-		EfiSecureBootEnabled:   pulumi.BoolPtrFromPtr(templateVm.EfiSecureBootEnabled),
+		Disks:                  disks,
+		NetworkInterfaces:      networkInterfaces,
+		Tags:                   tagIds,
+		CustomAttributes:       customAttributes,
 		EnableLogging:          pulumi.Bool(vsphereCfg.EnableLogging),
 		EnableDiskUuid:         pulumi.Bool(vsphereCfg.EnableDiskUuid),
-		Firmware:               pulumi.StringPtrFromPtr(templateVm.Firmware),
+		ExtraConfig:            toStringMapInput(extraConfig),
 		Folder:                 pulumi.String(vsphereCfg.VmsFolder),
 		GuestId:                pulumi.String("ubuntu64Guest"),
 		WaitForGuestIpTimeout:  pulumi.Int(net_timeout),
 		WaitForGuestNetTimeout: pulumi.Int(net_timeout),
-	}, opts...)
+	}
+
+	switch {
+	case templateVm != nil:
+		vmArgs.EfiSecureBootEnabled = pulumi.BoolPtrFromPtr(templateVm.EfiSecureBootEnabled)
+		vmArgs.Firmware = pulumi.StringPtrFromPtr(templateVm.Firmware)
+		cloneArgs, err := getVMCloneArgs(templateVm.Id, networkCfg, vm)
+		if err != nil {
+			return nil, err
+		}
+		vmArgs.Clone = cloneArgs
+	case lookupData.ContentLibraryItem != nil:
+		cloneArgs, err := getVMCloneArgs(lookupData.ContentLibraryItem.Id, networkCfg, vm)
+		if err != nil {
+			return nil, err
+		}
+		vmArgs.Clone = cloneArgs
+	default:
+		return nil, fmt.Errorf("vm %s: no resolved template source", vm.Name)
+	}
+
+	resourceName, aliases := retryResourceNameAndAliases(vm.Name, retryPolicy, attempt)
+	if len(aliases) > 0 {
+		opts = append(opts, pulumi.Aliases(aliases))
+	}
+
+	newVm, err := vsphere.NewVirtualMachine(ctx, resourceName, vmArgs, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return newVm, nil
 }
 
-// getVmCloneNetworkInterfaceArray creates a network interface array for the new VM.
-func getVmCloneNetworkInterfaceArray(templateVm *vsphere.LookupVirtualMachineResult, network *vsphere.GetNetworkResult) vsphere.VirtualMachineNetworkInterfaceArray {
-	return vsphere.VirtualMachineNetworkInterfaceArray{
-		&vsphere.VirtualMachineNetworkInterfaceArgs{
-			AdapterType: pulumi.String(templateVm.NetworkInterfaces[0].AdapterType),
+// toStringMapInput converts a plain string map to a pulumi.MapInput, as
+// required by fields such as VirtualMachineArgs.ExtraConfig.
+func toStringMapInput(m map[string]string) pulumi.MapInput {
+	out := pulumi.Map{}
+	for k, v := range m {
+		out[k] = pulumi.String(v)
+	}
+	return out
+}
+
+// getVmCloneNetworkInterfaceArray creates the network interface array for the new VM
+// from vm.NetworkInterfaces, resolving each interface's network by name via lookupData.Network.
+func getVmCloneNetworkInterfaceArray(lookupData *LookupData, vm VmData) (vsphere.VirtualMachineNetworkInterfaceArray, error) {
+	if len(vm.NetworkInterfaces) == 0 {
+		return nil, fmt.Errorf("vm %s: at least one network interface is required", vm.Name)
+	}
+
+	nics := vsphere.VirtualMachineNetworkInterfaceArray{}
+	for _, nic := range vm.NetworkInterfaces {
+		network, ok := lookupData.Network[nic.NetworkName]
+		if !ok {
+			return nil, fmt.Errorf("vm %s: network %q was not resolved during lookup", vm.Name, nic.NetworkName)
+		}
+		nicArgs := &vsphere.VirtualMachineNetworkInterfaceArgs{
+			AdapterType: pulumi.String(nic.AdapterType),
 			NetworkId:   pulumi.String(network.Id),
-		},
+		}
+		if nic.MacAddress != "" {
+			nicArgs.UseStaticMac = pulumi.Bool(true)
+			nicArgs.MacAddress = pulumi.String(nic.MacAddress)
+		}
+		nics = append(nics, nicArgs)
 	}
+	return nics, nil
 }
 
-// getVmCloneDiskArray creates a disk array for the new VM.
-func getVmCloneDiskArray(templateVm *vsphere.LookupVirtualMachineResult) vsphere.VirtualMachineDiskArray {
-	return vsphere.VirtualMachineDiskArray{
-		&vsphere.VirtualMachineDiskArgs{
-			Label:           pulumi.String("disk0"),
-			EagerlyScrub:    pulumi.Bool(templateVm.Disks[0].EagerlyScrub),
-			Size:            pulumi.Int(templateVm.Disks[0].Size),
-			ThinProvisioned: pulumi.Bool(templateVm.Disks[0].ThinProvisioned),
-		},
+// getVmCloneDiskArray creates the disk array for the new VM from vm.Disks. When vm.Disks
+// is empty and templateVm is set (inventory template source), it falls back to a single
+// disk0 cloned from the template, matching the template's size, provisioning, and scrub
+// settings. Content Library template sources have no disk0 to default from, so vm.Disks
+// must be set explicitly in that case.
+func getVmCloneDiskArray(lookupData *LookupData, templateVm *vsphere.LookupVirtualMachineResult, vm VmData) (vsphere.VirtualMachineDiskArray, error) {
+	if len(vm.Disks) == 0 {
+		if templateVm == nil {
+			return nil, fmt.Errorf("vm %s: disks must be set explicitly when cloning from a content library item", vm.Name)
+		}
+		return vsphere.VirtualMachineDiskArray{
+			&vsphere.VirtualMachineDiskArgs{
+				Label:           pulumi.String("disk0"),
+				EagerlyScrub:    pulumi.Bool(templateVm.Disks[0].EagerlyScrub),
+				Size:            pulumi.Int(templateVm.Disks[0].Size),
+				ThinProvisioned: pulumi.Bool(templateVm.Disks[0].ThinProvisioned),
+			},
+		}, nil
 	}
+
+	disks := vsphere.VirtualMachineDiskArray{}
+	for i, disk := range vm.Disks {
+		diskArgs := &vsphere.VirtualMachineDiskArgs{
+			Label:           pulumi.String(disk.Label),
+			UnitNumber:      pulumi.Int(i),
+			Size:            pulumi.Int(disk.SizeGb),
+			ThinProvisioned: pulumi.Bool(disk.ThinProvisioned),
+			EagerlyScrub:    pulumi.Bool(disk.EagerlyScrub),
+		}
+		if disk.ControllerType != "" {
+			diskArgs.ControllerType = pulumi.String(disk.ControllerType)
+		}
+		if disk.Datastore != "" {
+			override, ok := lookupData.Datastores[disk.Datastore]
+			if !ok {
+				return nil, fmt.Errorf("vm %s: datastore %q was not resolved during lookup", vm.Name, disk.Datastore)
+			}
+			diskArgs.DatastoreId = pulumi.String(override.Id)
+		}
+		disks = append(disks, diskArgs)
+	}
+	return disks, nil
 }
 
-// getVMCloneArgs creates the clone arguments for the new VM.
-func getVMCloneArgs(lookupData *LookupData, networkCfg NetworkCfg, vm VmData) *vsphere.VirtualMachineCloneArgs {
+// getVMCloneArgs creates the clone arguments for the new VM, cloning from the resolved
+// template identified by templateUuid (an inventory VM template or a Content Library
+// item of either type). It builds one customize network_interface block per entry in
+// vm.NetworkInterfaces, as required by the vsphere provider, and derives the overall
+// IPv4/IPv6 gateway from whichever interface declares one.
+func getVMCloneArgs(templateUuid string, networkCfg NetworkCfg, vm VmData) (*vsphere.VirtualMachineCloneArgs, error) {
+	if len(vm.NetworkInterfaces) == 0 {
+		return nil, fmt.Errorf("vm %s: at least one network interface is required", vm.Name)
+	}
+
+	customizeNics := vsphere.VirtualMachineCloneCustomizeNetworkInterfaceArray{}
+	var ipv4Gateway, ipv6Gateway string
+	for _, nic := range vm.NetworkInterfaces {
+		nicArgs := &vsphere.VirtualMachineCloneCustomizeNetworkInterfaceArgs{
+			DnsDomain:      pulumi.String(networkCfg.Domain),
+			DnsServerLists: toStringArray(networkCfg.DnsServers),
+		}
+		if !nic.Dhcp {
+			if nic.Ipv4Address == "" || nic.Ipv4PrefixLen == 0 {
+				return nil, fmt.Errorf("vm %s: network interface %q is not DHCP and requires ipv4Address and ipv4PrefixLen", vm.Name, nic.NetworkName)
+			}
+			nicArgs.Ipv4Address = pulumi.String(nic.Ipv4Address)
+			nicArgs.Ipv4Netmask = pulumi.Int(nic.Ipv4PrefixLen)
+			if nic.Ipv6Address != "" {
+				nicArgs.Ipv6Address = pulumi.String(nic.Ipv6Address)
+				nicArgs.Ipv6Netmask = pulumi.Int(nic.Ipv6PrefixLen)
+			}
+		}
+		if nic.Ipv4Gateway != "" {
+			ipv4Gateway = nic.Ipv4Gateway
+		}
+		if nic.Ipv6Gateway != "" {
+			ipv6Gateway = nic.Ipv6Gateway
+		}
+		customizeNics = append(customizeNics, nicArgs)
+	}
+
 	return &vsphere.VirtualMachineCloneArgs{
-		TemplateUuid: pulumi.String(lookupData.TemplateVm.Id),
+		TemplateUuid: pulumi.String(templateUuid),
 		Customize: &vsphere.VirtualMachineCloneCustomizeArgs{
 			DnsServerLists: toStringArray(networkCfg.DnsServers),
 			DnsSuffixLists: toStringArray(networkCfg.DnsSuffixes),
-			Ipv4Gateway:    pulumi.String(networkCfg.Gateway),
+			Ipv4Gateway:    pulumi.String(ipv4Gateway),
+			Ipv6Gateway:    pulumi.String(ipv6Gateway),
 			LinuxOptions: &vsphere.VirtualMachineCloneCustomizeLinuxOptionsArgs{
 				Domain:   pulumi.String(networkCfg.Domain),
 				HostName: pulumi.String(vm.HostName),
 			},
-			NetworkInterfaces: vsphere.VirtualMachineCloneCustomizeNetworkInterfaceArray{
-				&vsphere.VirtualMachineCloneCustomizeNetworkInterfaceArgs{
-					DnsDomain:      pulumi.String(networkCfg.Domain),
-					DnsServerLists: toStringArray(networkCfg.DnsServers),
-					Ipv4Address:    pulumi.String(vm.Ipv4Address),
-					Ipv4Netmask:    pulumi.Int(networkCfg.Mask),
-				},
-			},
+			NetworkInterfaces: customizeNics,
 		},
-	}
+	}, nil
 }
 
 // toStringArray converts a string slice to a pulumi.StringArray.
@@ -196,8 +476,12 @@ func toStringArray(list []string) pulumi.StringArray {
 	return dnsSuffixes
 }
 
-// lookupData performs the necessary vSphere lookups to get the required resources for VM creation.
-func lookupData(ctx *pulumi.Context, vsphereCfg VsphereCfg, networkCfg NetworkCfg) (*LookupData, error) {
+// lookupData performs the necessary vSphere lookups to get the required resources for VM creation,
+// resolving every network, tag, and custom attribute referenced across vms exactly once,
+// along with the template source and placement configured in args.
+func lookupData(ctx *pulumi.Context, args *VsphereVmsArgs, vms []VmData, opts ...pulumi.ResourceOption) (*LookupData, error) {
+	vsphereCfg := args.VsphereCfg
+
 	datacenter, err := vsphere.LookupDatacenter(ctx, &vsphere.LookupDatacenterArgs{
 		Name: pulumi.StringRef(vsphereCfg.Datacenter),
 	}, nil)
@@ -218,26 +502,116 @@ func lookupData(ctx *pulumi.Context, vsphereCfg VsphereCfg, networkCfg NetworkCf
 	if err != nil {
 		return nil, err
 	}
-	templateVm, err := vsphere.LookupVirtualMachine(ctx, &vsphere.LookupVirtualMachineArgs{
-		DatacenterId: pulumi.StringRef(datacenter.Id),
-		Name:         pulumi.StringRef(vsphereCfg.TemplateName),
-		Folder:       pulumi.StringRef(vsphereCfg.TemplateFolder),
-	})
+	templateVm, contentLibraryItem, err := lookupTemplateSource(ctx, datacenter, vsphereCfg.Template)
 	if err != nil {
 		return nil, err
 	}
-	network, err := vsphere.GetNetwork(ctx, &vsphere.GetNetworkArgs{
-		DatacenterId: pulumi.StringRef(datacenter.Id),
-		Name:         vsphereCfg.NetworkName,
-	})
+
+	datastores := map[string]*vsphere.GetDatastoreResult{}
+	for _, vm := range vms {
+		for _, disk := range vm.Disks {
+			if disk.Datastore == "" {
+				continue
+			}
+			if _, ok := datastores[disk.Datastore]; ok {
+				continue
+			}
+			override, err := vsphere.GetDatastore(ctx, &vsphere.GetDatastoreArgs{
+				DatacenterId: pulumi.StringRef(datacenter.Id),
+				Name:         disk.Datastore,
+			})
+			if err != nil {
+				return nil, err
+			}
+			datastores[disk.Datastore] = override
+		}
+	}
+
+	networks := map[string]*vsphere.GetNetworkResult{}
+	for _, vm := range vms {
+		for _, nic := range vm.NetworkInterfaces {
+			if _, ok := networks[nic.NetworkName]; ok {
+				continue
+			}
+			network, err := vsphere.GetNetwork(ctx, &vsphere.GetNetworkArgs{
+				DatacenterId: pulumi.StringRef(datacenter.Id),
+				Name:         nic.NetworkName,
+			})
+			if err != nil {
+				return nil, err
+			}
+			networks[nic.NetworkName] = network
+		}
+	}
+
+	resourcePoolId, hostSystemId, err := resolvePlacement(ctx, datacenter, cluster, vsphereCfg, args.Placement)
+	if err != nil {
+		return nil, err
+	}
+
+	var tagRefs []string
+	var customAttributeNames []string
+	for _, vm := range vms {
+		tagRefs = append(tagRefs, vm.Tags...)
+		for name := range vm.CustomAttributes {
+			customAttributeNames = append(customAttributeNames, name)
+		}
+	}
+	tagIds, err := resolveTagIds(ctx, args.ManageTags, tagRefs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	customAttributeIds, err := resolveCustomAttributeIds(ctx, customAttributeNames)
 	if err != nil {
 		return nil, err
 	}
+
 	return &LookupData{
-		Datacenter: datacenter,
-		Cluster:    cluster,
-		Datastore:  datastore,
-		TemplateVm: templateVm,
-		Network:    network,
+		Datacenter:         datacenter,
+		Cluster:            cluster,
+		Datastore:          datastore,
+		Datastores:         datastores,
+		TemplateVm:         templateVm,
+		ContentLibraryItem: contentLibraryItem,
+		Network:            networks,
+		ResourcePoolId:     resourcePoolId,
+		HostSystemId:       hostSystemId,
+		TagIds:             tagIds,
+		CustomAttributeIds: customAttributeIds,
 	}, nil
 }
+
+// lookupTemplateSource resolves source per VsphereCfg.Template, returning either a
+// looked-up inventory VM template or a resolved Content Library item, never both.
+func lookupTemplateSource(ctx *pulumi.Context, datacenter *vsphere.LookupDatacenterResult, source TemplateSource) (*vsphere.LookupVirtualMachineResult, *vsphere.GetContentLibraryItemResult, error) {
+	switch {
+	case source.Inventory != nil:
+		templateVm, err := vsphere.LookupVirtualMachine(ctx, &vsphere.LookupVirtualMachineArgs{
+			DatacenterId: pulumi.StringRef(datacenter.Id),
+			Name:         pulumi.StringRef(source.Inventory.Name),
+			Folder:       pulumi.StringRef(source.Inventory.Folder),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return templateVm, nil, nil
+	case source.ContentLibrary != nil:
+		library, err := vsphere.GetContentLibrary(ctx, &vsphere.GetContentLibraryArgs{
+			Name: pulumi.StringRef(source.ContentLibrary.Library),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		item, err := vsphere.GetContentLibraryItem(ctx, &vsphere.GetContentLibraryItemArgs{
+			Name:    pulumi.StringRef(source.ContentLibrary.Item),
+			Library: pulumi.StringRef(library.Id),
+			Type:    pulumi.StringRef(source.ContentLibrary.Type),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, item, nil
+	default:
+		return nil, nil, fmt.Errorf("vsphereCfg.template: exactly one of inventory or contentLibrary must be set")
+	}
+}